@@ -5,18 +5,64 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/oauth2"
 )
 
+// StoredToken is the data a TokenStore persists across process restarts.
+// It mirrors the fields of oauth2.Token explicitly, plus RefreshExpiry,
+// rather than handing callers a bare *oauth2.Token: oauth2.Token keeps
+// everything beyond its standard fields in an unexported Extra map that
+// json.Marshal silently drops, which would lose RefreshExpiry on any
+// ordinary JSON-backed TokenStore and make the refresh-token flow fall
+// back to username/password on every restart.
+type StoredToken struct {
+	AccessToken   string    `json:"access_token"`
+	TokenType     string    `json:"token_type"`
+	RefreshToken  string    `json:"refresh_token"`
+	Expiry        time.Time `json:"expiry"`
+	RefreshExpiry time.Time `json:"refresh_expiry"`
+}
+
+func (t *StoredToken) oauth2Token() *oauth2.Token {
+	return &oauth2.Token{
+		AccessToken:  t.AccessToken,
+		TokenType:    t.TokenType,
+		RefreshToken: t.RefreshToken,
+		Expiry:       t.Expiry,
+	}
+}
+
+func (t *StoredToken) refreshExpired() bool {
+	return time.Now().After(t.RefreshExpiry)
+}
+
+// TokenStore persists oauth2 tokens across process restarts, so Client
+// doesn't need to re-authenticate with username and password every time it
+// starts up.
+type TokenStore interface {
+	// Load returns the last saved token, or a nil token if none has been
+	// saved yet.
+	Load() (*StoredToken, error)
+	// Save persists t, overwriting any previously saved token.
+	Save(t *StoredToken) error
+}
+
 type tokenSource struct {
 	baseURL  string
 	username string
 	password string
+	store    TokenStore
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	current *StoredToken
 }
 
 type tokenPayload struct {
@@ -24,6 +70,10 @@ type tokenPayload struct {
 	Password string `json:"password"`
 }
 
+type refreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type tokenResponse struct {
 	AccessToken      string    `json:"access_token"`
 	ExpiresIn        int       `json:"expires_in"`
@@ -35,23 +85,57 @@ type tokenResponse struct {
 	Scope            string    `json:"scope"`
 }
 
+// Token implements oauth2.TokenSource. It prefers refreshing the current
+// token over a fresh username/password login, falling back to login if
+// there is no refresh token, it has expired, or the refresh request fails.
 func (s *tokenSource) Token() (*oauth2.Token, error) {
-	payload := &bytes.Buffer{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current != nil && s.current.RefreshToken != "" && !s.current.refreshExpired() {
+		t, err := s.request("homely/oauth/refresh-token", refreshTokenPayload{RefreshToken: s.current.RefreshToken})
+		if err == nil {
+			s.current = t
+			s.save(t)
+			return t.oauth2Token(), nil
+		}
+
+		s.logger.Warn("Refreshing Homely token failed, falling back to username/password login", "error", err)
+	}
 
-	err := json.NewEncoder(payload).Encode(tokenPayload{
-		Username: s.username,
-		Password: s.password,
-	})
+	t, err := s.request("homely/oauth/token", tokenPayload{Username: s.username, Password: s.password})
 	if err != nil {
+		return nil, err
+	}
+
+	s.current = t
+	s.save(t)
+
+	return t.oauth2Token(), nil
+}
+
+func (s *tokenSource) save(t *StoredToken) {
+	if s.store == nil {
+		return
+	}
+
+	if err := s.store.Save(t); err != nil {
+		s.logger.Warn("Failed to persist Homely token", "error", err)
+	}
+}
+
+func (s *tokenSource) request(resource string, payload any) (*StoredToken, error) {
+	body := &bytes.Buffer{}
+	if err := json.NewEncoder(body).Encode(payload); err != nil {
 		return nil, fmt.Errorf("encode token request body: %w", err)
 	}
 
-	path, err := url.JoinPath(s.baseURL, "homely/oauth/token")
+	path, err := url.JoinPath(s.baseURL, resource)
 	if err != nil {
 		return nil, fmt.Errorf("create token URL: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, path, payload)
+	req, err := http.NewRequest(http.MethodPost, path, body)
 	if err != nil {
 		return nil, fmt.Errorf("created token request: %w", err)
 	}
@@ -74,22 +158,21 @@ func (s *tokenSource) Token() (*oauth2.Token, error) {
 	}
 
 	var r tokenResponse
-
 	if err := json.Unmarshal(bodyBytes, &r); err != nil {
 		return nil, fmt.Errorf("unmarshal token response: %w", err)
 	}
 
-	t := &oauth2.Token{
-		AccessToken:  r.AccessToken,
-		TokenType:    r.TokenType,
-		RefreshToken: r.RefreshToken,
-		Expiry:       time.Now().Add(time.Duration(r.ExpiresIn)),
-	}
+	return buildToken(r), nil
+}
+
+func buildToken(r tokenResponse) *StoredToken {
+	now := time.Now()
 
-	return t.WithExtra(map[string]any{
-		"refresh_expires_in": r.RefreshExpiresIn,
-		"not_before_policy":  r.NotBeforePolicy,
-		"session_state":      r.SessionState,
-		"scope":              r.Scope,
-	}), nil
+	return &StoredToken{
+		AccessToken:   r.AccessToken,
+		TokenType:     r.TokenType,
+		RefreshToken:  r.RefreshToken,
+		Expiry:        now.Add(time.Duration(r.ExpiresIn) * time.Second),
+		RefreshExpiry: now.Add(time.Duration(r.RefreshExpiresIn) * time.Second),
+	}
 }
@@ -0,0 +1,155 @@
+package homely
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRefreshExpired(t *testing.T) {
+	cases := []struct {
+		name string
+		t    StoredToken
+		want bool
+	}{
+		{"in the future", StoredToken{RefreshExpiry: time.Now().Add(time.Hour)}, false},
+		{"in the past", StoredToken{RefreshExpiry: time.Now().Add(-time.Hour)}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.t.refreshExpired(); got != c.want {
+				t.Errorf("refreshExpired() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestBuildToken(t *testing.T) {
+	before := time.Now()
+
+	tok := buildToken(tokenResponse{
+		AccessToken:      "access",
+		RefreshToken:     "refresh",
+		TokenType:        "Bearer",
+		ExpiresIn:        60,
+		RefreshExpiresIn: 3600,
+	})
+
+	if tok.AccessToken != "access" || tok.RefreshToken != "refresh" || tok.TokenType != "Bearer" {
+		t.Fatalf("buildToken copied fields incorrectly: %+v", tok)
+	}
+
+	if d := tok.Expiry.Sub(before); d < 59*time.Second || d > 61*time.Second {
+		t.Errorf("Expiry = %v after before, want ~60s", d)
+	}
+
+	if d := tok.RefreshExpiry.Sub(before); d < 3599*time.Second || d > 3601*time.Second {
+		t.Errorf("RefreshExpiry = %v after before, want ~3600s", d)
+	}
+}
+
+// tokenTestServer serves homely/oauth/token and homely/oauth/refresh-token,
+// handing refresh requests to refresh and login requests to login.
+func tokenTestServer(t *testing.T, refresh, login func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/homely/oauth/refresh-token", func(w http.ResponseWriter, r *http.Request) {
+		refresh(w, r)
+	})
+	mux.HandleFunc("/homely/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		login(w, r)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func writeTokenResponse(w http.ResponseWriter, accessToken string) {
+	json.NewEncoder(w).Encode(tokenResponse{
+		AccessToken:      accessToken,
+		RefreshToken:     "new-refresh",
+		ExpiresIn:        60,
+		RefreshExpiresIn: 3600,
+	})
+}
+
+func TestTokenSourceTokenRefreshSuccess(t *testing.T) {
+	srv := tokenTestServer(t,
+		func(w http.ResponseWriter, r *http.Request) { writeTokenResponse(w, "refreshed") },
+		func(w http.ResponseWriter, r *http.Request) { t.Fatal("unexpected login request") },
+	)
+
+	s := &tokenSource{
+		baseURL: srv.URL,
+		logger:  discardLogger(),
+		current: &StoredToken{RefreshToken: "old-refresh", RefreshExpiry: time.Now().Add(time.Hour)},
+	}
+
+	tok, err := s.Token()
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	if tok.AccessToken != "refreshed" {
+		t.Errorf("AccessToken = %q, want %q", tok.AccessToken, "refreshed")
+	}
+}
+
+func TestTokenSourceTokenRefreshFailureFallsBackToLogin(t *testing.T) {
+	srv := tokenTestServer(t,
+		func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusUnauthorized) },
+		func(w http.ResponseWriter, r *http.Request) { writeTokenResponse(w, "logged-in") },
+	)
+
+	s := &tokenSource{
+		baseURL:  srv.URL,
+		username: "user",
+		password: "pass",
+		logger:   discardLogger(),
+		current:  &StoredToken{RefreshToken: "old-refresh", RefreshExpiry: time.Now().Add(time.Hour)},
+	}
+
+	tok, err := s.Token()
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	if tok.AccessToken != "logged-in" {
+		t.Errorf("AccessToken = %q, want %q", tok.AccessToken, "logged-in")
+	}
+}
+
+func TestTokenSourceTokenNoRefreshToken(t *testing.T) {
+	srv := tokenTestServer(t,
+		func(w http.ResponseWriter, r *http.Request) { t.Fatal("unexpected refresh request") },
+		func(w http.ResponseWriter, r *http.Request) { writeTokenResponse(w, "logged-in") },
+	)
+
+	s := &tokenSource{
+		baseURL:  srv.URL,
+		username: "user",
+		password: "pass",
+		logger:   discardLogger(),
+	}
+
+	tok, err := s.Token()
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+
+	if tok.AccessToken != "logged-in" {
+		t.Errorf("AccessToken = %q, want %q", tok.AccessToken, "logged-in")
+	}
+}
@@ -21,12 +21,26 @@ type Client struct {
 	http        http.Client
 	tokenSource oauth2.TokenSource
 	baseURL     string
+	logger      *slog.Logger
+	metrics     socketio.Metrics
 }
 
 type Config struct {
 	Username string
 	Password string
 	BaseURL  string
+
+	// TokenStore, if set, persists the oauth2 token across process restarts
+	// so Client doesn't have to log in with username/password every time.
+	TokenStore TokenStore
+
+	// Logger receives library log output. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	// Metrics, if set, receives counters and observations about the
+	// underlying Socket.IO connection used by Stream (pings, reconnects,
+	// decode errors).
+	Metrics socketio.Metrics
 }
 
 type Location struct {
@@ -93,16 +107,43 @@ func New(c Config) *Client {
 		c.BaseURL = defaultBaseURL
 	}
 
-	ts := oauth2.ReuseTokenSource(nil, &tokenSource{
+	logger := c.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	var preloaded *StoredToken
+	if c.TokenStore != nil {
+		t, err := c.TokenStore.Load()
+		if err != nil {
+			logger.Warn("Failed to load stored Homely token", "error", err)
+		} else {
+			preloaded = t
+		}
+	}
+
+	src := &tokenSource{
 		baseURL:  c.BaseURL,
 		username: c.Username,
 		password: c.Password,
-	})
+		store:    c.TokenStore,
+		logger:   logger,
+		current:  preloaded,
+	}
+
+	var initial *oauth2.Token
+	if preloaded != nil {
+		initial = preloaded.oauth2Token()
+	}
+
+	ts := oauth2.ReuseTokenSource(initial, src)
 
 	return &Client{
 		tokenSource: ts,
 		http:        *oauth2.NewClient(context.Background(), ts),
 		baseURL:     c.BaseURL,
+		logger:      logger,
+		metrics:     c.Metrics,
 	}
 }
 
@@ -136,21 +177,26 @@ func (c *Client) LocationDetails(ctx context.Context, locationID uuid.UUID) (Loc
 }
 
 func (c *Client) Stream(ctx context.Context, locationID uuid.UUID, h func(e Event)) error {
-	sio := socketio.New(fmt.Sprintf("%s/socket.io/?locationId=%s", c.baseURL, locationID), c.tokenSource)
-	return sio.HandleEvents(ctx, func(name, msg string) error {
+	sio := socketio.New(
+		fmt.Sprintf("%s/socket.io/?locationId=%s", c.baseURL, locationID),
+		c.tokenSource,
+		socketio.WithLogger(c.logger),
+		socketio.WithMetrics(c.metrics),
+	)
+	return sio.HandleEvents(ctx, func(name, msg string) ([]any, error) {
 		if name != "event" {
-			slog.Warn("Got non event event", "name", name)
-			return nil
+			c.logger.Warn("Got non event event", "name", name)
+			return nil, nil
 		}
 
 		var e Event
 		if err := json.Unmarshal([]byte(msg), &e); err != nil {
-			return fmt.Errorf("unmarshal event: %w", err)
+			return nil, fmt.Errorf("unmarshal event: %w", err)
 		}
 
 		h(e)
 
-		return nil
+		return nil, nil
 	})
 }
 
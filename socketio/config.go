@@ -0,0 +1,39 @@
+package socketio
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Metrics receives counters and observations about a Client's connection,
+// so callers can wire up Prometheus, OpenTelemetry, or whatever else
+// without this package importing any of it.
+type Metrics interface {
+	// ObservePingLatency reports the time elapsed since the previous
+	// EngineIO ping was received.
+	ObservePingLatency(d time.Duration)
+	// IncEventsReceived is called once per dispatched SocketIO event, named
+	// by its event name.
+	IncEventsReceived(name string)
+	// IncReconnect is called once per reconnect attempt.
+	IncReconnect()
+	// IncDecodeError is called whenever an incoming packet fails to decode.
+	IncDecodeError()
+}
+
+// WithLogger overrides the logger Client uses. Defaults to slog.Default().
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.logger = l }
+}
+
+// WithMetrics registers m to receive connection metrics. There is no metrics
+// collection by default.
+func WithMetrics(m Metrics) Option {
+	return func(c *Client) { c.metrics = m }
+}
+
+func (c *Client) incDecodeError() {
+	if c.metrics != nil {
+		c.metrics.IncDecodeError()
+	}
+}
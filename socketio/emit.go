@@ -0,0 +1,137 @@
+package socketio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Emit sends a SocketIO event packet carrying args to the server without
+// waiting for an acknowledgement.
+func (c *Client) Emit(ctx context.Context, event string, args ...any) error {
+	conn := c.activeConn()
+	if conn == nil {
+		return fmt.Errorf("emit %q: not connected", event)
+	}
+
+	payload, err := encodeEventArgs(event, args)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+
+	return conn.Write(ctx, fmt.Sprintf("%d%d%s", EIOPacketTypeMessage, PacketTypeEvent, payload))
+}
+
+// EmitWithAck sends a SocketIO event packet carrying args and waits up to
+// timeout for the server's acknowledgement, returning the values it sent
+// back.
+func (c *Client) EmitWithAck(ctx context.Context, event string, timeout time.Duration, args ...any) ([]json.RawMessage, error) {
+	conn := c.activeConn()
+	if conn == nil {
+		return nil, fmt.Errorf("emit %q: not connected", event)
+	}
+
+	payload, err := encodeEventArgs(event, args)
+	if err != nil {
+		return nil, fmt.Errorf("encode event: %w", err)
+	}
+
+	id := c.nextAckID()
+	ch := make(chan []json.RawMessage, 1)
+
+	c.acksMu.Lock()
+	c.acks[id] = ch
+	c.acksMu.Unlock()
+
+	defer func() {
+		c.acksMu.Lock()
+		delete(c.acks, id)
+		c.acksMu.Unlock()
+	}()
+
+	if err := conn.Write(ctx, fmt.Sprintf("%d%d%d%s", EIOPacketTypeMessage, PacketTypeEvent, id, payload)); err != nil {
+		return nil, fmt.Errorf("write event: %w", err)
+	}
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case values := <-ch:
+		return values, nil
+	case <-t.C:
+		return nil, fmt.Errorf("emit %q: timed out waiting for ack", event)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func encodeEventArgs(event string, args []any) (string, error) {
+	values := make([]any, 0, len(args)+1)
+	values = append(values, event)
+	values = append(values, args...)
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// parseAckID splits the payload following a SocketIO packet type digit into
+// its optional ack id and the remaining JSON payload, e.g. "12[...]" becomes
+// (12, "[...]") and "[...]" becomes (nil, "[...]").
+func parseAckID(s string) (*int, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	if i == 0 {
+		return nil, s
+	}
+
+	var id int
+	for _, r := range s[:i] {
+		id = id*10 + int(r-'0')
+	}
+
+	return &id, s[i:]
+}
+
+func (c *Client) nextAckID() int {
+	return int(atomic.AddInt64(&c.ackSeq, 1))
+}
+
+func (c *Client) resolveAck(id int, values []json.RawMessage) {
+	c.acksMu.Lock()
+	ch, ok := c.acks[id]
+	c.acksMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	select {
+	case ch <- values:
+	default:
+	}
+}
+
+// sendAck replies to a server event that carried ack id with values,
+// encoded as a SocketIO ack packet.
+func (c *Client) sendAck(ctx context.Context, conn frameConn, id int, values []any) error {
+	if values == nil {
+		values = []any{}
+	}
+
+	b, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("encode ack: %w", err)
+	}
+
+	return conn.Write(ctx, fmt.Sprintf("%d%d%d%s", EIOPacketTypeMessage, PacketTypeAck, id, string(b)))
+}
@@ -0,0 +1,76 @@
+package socketio
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAckID(t *testing.T) {
+	cases := []struct {
+		name     string
+		in       string
+		wantID   *int
+		wantRest string
+	}{
+		{"no id", `["event",{}]`, nil, `["event",{}]`},
+		{"with id", `12["event",{}]`, intPtr(12), `["event",{}]`},
+		{"id zero", `0["a"]`, intPtr(0), `["a"]`},
+		{"empty", "", nil, ""},
+		{"digits only", "42", intPtr(42), ""},
+		{"leading zero stays numeric", "02[]", intPtr(2), "[]"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			id, rest := parseAckID(c.in)
+
+			if (id == nil) != (c.wantID == nil) {
+				t.Fatalf("parseAckID(%q) id = %v, want %v", c.in, id, c.wantID)
+			}
+			if id != nil && *id != *c.wantID {
+				t.Errorf("parseAckID(%q) id = %d, want %d", c.in, *id, *c.wantID)
+			}
+			if rest != c.wantRest {
+				t.Errorf("parseAckID(%q) rest = %q, want %q", c.in, rest, c.wantRest)
+			}
+		})
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestEncodeEventArgs(t *testing.T) {
+	payload, err := encodeEventArgs("test-event", []any{1, "two"})
+	if err != nil {
+		t.Fatalf("encodeEventArgs: %v", err)
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal([]byte(payload), &values); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+
+	if len(values) != 3 {
+		t.Fatalf("got %d values, want 3", len(values))
+	}
+
+	var name string
+	if err := json.Unmarshal(values[0], &name); err != nil {
+		t.Fatalf("unmarshal name: %v", err)
+	}
+
+	if name != "test-event" {
+		t.Errorf("name = %q, want %q", name, "test-event")
+	}
+}
+
+func TestEncodeEventArgsNoArgs(t *testing.T) {
+	payload, err := encodeEventArgs("ping", nil)
+	if err != nil {
+		t.Fatalf("encodeEventArgs: %v", err)
+	}
+
+	if payload != `["ping"]` {
+		t.Errorf("payload = %q, want %q", payload, `["ping"]`)
+	}
+}
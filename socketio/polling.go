@@ -0,0 +1,223 @@
+package socketio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/coder/websocket"
+)
+
+// TransportPolling selects the HTTP long-polling EngineIO transport instead
+// of the default websocket transport. Use it for servers or proxies that
+// block websocket upgrades; Client will still transparently switch to a
+// websocket afterwards if the server advertises support for it.
+const TransportPolling = "polling"
+
+// WithTransport selects which EngineIO transport Client dials with. The
+// zero value, and Transport ("websocket"), dial a websocket directly, as
+// before. TransportPolling starts on HTTP long-polling, which works behind
+// proxies that don't allow websocket upgrades.
+func WithTransport(t string) Option {
+	return func(c *Client) { c.transport = t }
+}
+
+// pollingRecordSeparator separates multiple EngineIO packets combined into
+// a single HTTP long-polling payload.
+const pollingRecordSeparator = "\x1e"
+
+// pollingConn implements frameConn over the EngineIO HTTP long-polling
+// transport: packets are received by repeatedly GETing the poll URL and
+// sent by POSTing to it.
+type pollingConn struct {
+	httpClient *http.Client
+	pollURL    url.URL
+	sid        string
+
+	mu       sync.Mutex
+	buffered []string
+}
+
+// dialPolling performs the EngineIO long-polling handshake: a single GET
+// returning a "0{...}" open packet carrying the session id that must be
+// attached, as the sid query param, to every subsequent request.
+func dialPolling(ctx context.Context, base url.URL) (*pollingConn, eioOpenPacket, error) {
+	p := &pollingConn{httpClient: http.DefaultClient, pollURL: base}
+
+	body, err := p.get(ctx)
+	if err != nil {
+		return nil, eioOpenPacket{}, fmt.Errorf("polling handshake: %w", err)
+	}
+
+	frames := splitFrames(body)
+	if len(frames) == 0 {
+		return nil, eioOpenPacket{}, fmt.Errorf("polling handshake: empty response")
+	}
+
+	open, err := parseOpenFrame(frames[0])
+	if err != nil {
+		return nil, eioOpenPacket{}, fmt.Errorf("polling handshake: %w", err)
+	}
+
+	p.sid = open.SID
+
+	q := p.pollURL.Query()
+	q.Set("sid", p.sid)
+	p.pollURL.RawQuery = q.Encode()
+
+	p.buffered = frames[1:]
+
+	return p, open, nil
+}
+
+func splitFrames(payload string) []string {
+	if payload == "" {
+		return nil
+	}
+
+	return strings.Split(payload, pollingRecordSeparator)
+}
+
+// parseOpenFrame parses the EngineIO "open" (type 0) frame sent as the
+// first frame of a long-polling handshake, e.g.
+// `0{"sid":"...","upgrades":["websocket"],"pingInterval":25000,"pingTimeout":20000}`.
+func parseOpenFrame(frame string) (eioOpenPacket, error) {
+	if len(frame) < 1 || PacketType(frame[0]-'0') != EIOPacketTypeOpen {
+		return eioOpenPacket{}, fmt.Errorf("expected EngineIO open packet, got %q", frame)
+	}
+
+	var open eioOpenPacket
+	if err := json.Unmarshal([]byte(frame[1:]), &open); err != nil {
+		return eioOpenPacket{}, fmt.Errorf("unmarshal open packet: %w", err)
+	}
+
+	return open, nil
+}
+
+func (p *pollingConn) get(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.pollURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("poll: unexpected status %s", resp.Status)
+	}
+
+	return string(b), nil
+}
+
+func (p *pollingConn) Read(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	if len(p.buffered) > 0 {
+		f := p.buffered[0]
+		p.buffered = p.buffered[1:]
+		p.mu.Unlock()
+		return f, nil
+	}
+	p.mu.Unlock()
+
+	body, err := p.get(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	frames := splitFrames(body)
+	if len(frames) == 0 {
+		return "", fmt.Errorf("poll: empty response")
+	}
+
+	p.mu.Lock()
+	p.buffered = frames[1:]
+	p.mu.Unlock()
+
+	return frames[0], nil
+}
+
+func (p *pollingConn) Write(ctx context.Context, data string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.pollURL.String(), strings.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("poll write: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (p *pollingConn) Close() error { return nil }
+
+// upgradeToWebsocket attempts to transparently switch an active
+// long-polling connection to a websocket, per the EngineIO upgrade
+// handshake: a "2probe" is sent over a new websocket dialed with the
+// existing sid, the server must answer "3probe", and only then is the "5"
+// upgrade packet sent and the websocket used for all further traffic.
+func upgradeToWebsocket(ctx context.Context, base url.URL, sid string) (frameConn, error) {
+	q := base.Query()
+	q.Set("transport", Transport)
+	q.Set("sid", sid)
+	base.RawQuery = q.Encode()
+	base.Scheme = wsScheme(base.Scheme)
+
+	conn, _, err := websocket.Dial(ctx, base.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("upgrade dial: %w", err)
+	}
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte("2probe")); err != nil {
+		conn.CloseNow()
+		return nil, fmt.Errorf("send probe: %w", err)
+	}
+
+	_, b, err := conn.Read(ctx)
+	if err != nil {
+		conn.CloseNow()
+		return nil, fmt.Errorf("read probe response: %w", err)
+	}
+
+	if string(b) != "3probe" {
+		conn.CloseNow()
+		return nil, fmt.Errorf("unexpected probe response: %q", b)
+	}
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte("5")); err != nil {
+		conn.CloseNow()
+		return nil, fmt.Errorf("send upgrade: %w", err)
+	}
+
+	return &wsConn{c: conn}, nil
+}
+
+func wsScheme(httpScheme string) string {
+	if httpScheme == "https" {
+		return "wss"
+	}
+
+	return "ws"
+}
@@ -0,0 +1,63 @@
+package socketio
+
+import "testing"
+
+func TestSplitFrames(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"single", "2", []string{"2"}},
+		{"multiple", "0{\"sid\":\"abc\"}\x1e2\x1e40", []string{`0{"sid":"abc"}`, "2", "40"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := splitFrames(c.in)
+			if len(got) != len(c.want) {
+				t.Fatalf("splitFrames(%q) = %v, want %v", c.in, got, c.want)
+			}
+
+			for i := range got {
+				if got[i] != c.want[i] {
+					t.Errorf("splitFrames(%q)[%d] = %q, want %q", c.in, i, got[i], c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseOpenFrame(t *testing.T) {
+	open, err := parseOpenFrame(`0{"sid":"abc123","upgrades":["websocket"],"pingInterval":25000,"pingTimeout":20000}`)
+	if err != nil {
+		t.Fatalf("parseOpenFrame: %v", err)
+	}
+
+	if open.SID != "abc123" {
+		t.Errorf("SID = %q, want %q", open.SID, "abc123")
+	}
+
+	if open.PingInterval != 25000 || open.PingTimeout != 20000 {
+		t.Errorf("got pingInterval=%d pingTimeout=%d, want 25000/20000", open.PingInterval, open.PingTimeout)
+	}
+
+	if len(open.Upgrades) != 1 || open.Upgrades[0] != "websocket" {
+		t.Errorf("Upgrades = %v, want [websocket]", open.Upgrades)
+	}
+}
+
+func TestParseOpenFrameErrors(t *testing.T) {
+	cases := []string{
+		"",
+		"4{}",      // not an open packet
+		`0{"sid":`, // truncated JSON
+	}
+
+	for _, in := range cases {
+		if _, err := parseOpenFrame(in); err == nil {
+			t.Errorf("parseOpenFrame(%q) = nil error, want error", in)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package socketio
+
+import (
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy controls how a Client reconnects after losing its
+// underlying connection. Delays grow exponentially from InitialDelay up to
+// MaxDelay, with random Jitter added to avoid reconnect storms when many
+// clients drop at once.
+type ReconnectPolicy struct {
+	// InitialDelay is the delay before the first reconnect attempt.
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between reconnect attempts before Jitter is
+	// added, so the actual delay can exceed it by up to that fraction.
+	MaxDelay time.Duration
+	// Jitter is the fraction, between 0 and 1, of the computed delay that is
+	// added on top at random.
+	Jitter float64
+	// MaxAttempts limits how many consecutive reconnect attempts
+	// HandleEvents makes before giving up. Zero means unlimited attempts.
+	MaxAttempts int
+}
+
+// DefaultReconnectPolicy returns the ReconnectPolicy used by Client when none
+// is provided: a one second initial delay, doubling up to 30 seconds, with
+// 20% jitter and unlimited attempts.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		InitialDelay: time.Second,
+		MaxDelay:     30 * time.Second,
+		Jitter:       0.2,
+		MaxAttempts:  0,
+	}
+}
+
+func (p ReconnectPolicy) delay(attempt int) time.Duration {
+	if attempt > 30 {
+		// Avoid overflowing the shift below; MaxDelay will have kicked in
+		// long before attempt gets anywhere near this.
+		attempt = 30
+	}
+
+	d := p.InitialDelay * time.Duration(1<<attempt)
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		d += time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+
+	return d
+}
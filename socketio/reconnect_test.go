@@ -0,0 +1,47 @@
+package socketio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	p := ReconnectPolicy{InitialDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{2, 4 * time.Second},
+		{3, 8 * time.Second},
+		{4, 10 * time.Second}, // capped by MaxDelay
+		{100, 10 * time.Second},
+	}
+
+	for _, c := range cases {
+		if got := p.delay(c.attempt); got != c.want {
+			t.Errorf("delay(%d) = %v, want %v", c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestReconnectPolicyDelayJitter(t *testing.T) {
+	p := ReconnectPolicy{InitialDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		d := p.delay(0)
+		if d < time.Second || d > 1500*time.Millisecond {
+			t.Fatalf("delay with 0.5 jitter = %v, want within [1s, 1.5s]", d)
+		}
+	}
+}
+
+func TestReconnectPolicyDelayNoMaxDelay(t *testing.T) {
+	p := ReconnectPolicy{InitialDelay: time.Second}
+
+	if got, want := p.delay(3), 8*time.Second; got != want {
+		t.Errorf("delay(3) = %v, want %v", got, want)
+	}
+}
@@ -1,5 +1,5 @@
-// This package supports a tiny subset of the [Socket.IO] protocol.
-// For now it only supports websockets, not long-polling.
+// This package supports a tiny subset of the [Socket.IO] protocol, over
+// either a websocket or HTTP long-polling transport.
 //
 // [Socket.IO]: https://socket.io/docs/v4/socket-io-protocol
 package socketio
@@ -7,10 +7,13 @@ package socketio
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/coder/websocket"
 	"golang.org/x/oauth2"
@@ -44,132 +47,445 @@ const (
 	EIOPacketTypeNoop
 )
 
+// eioOpenPacket is the JSON payload carried by the EngineIO "open" (type 0)
+// packet the server sends right after the connection is established.
+type eioOpenPacket struct {
+	SID          string   `json:"sid"`
+	Upgrades     []string `json:"upgrades"`
+	PingInterval int      `json:"pingInterval"`
+	PingTimeout  int      `json:"pingTimeout"`
+}
+
+// frameConn abstracts reading and writing raw EngineIO packets, regardless
+// of whether the underlying transport is a websocket or HTTP long-polling.
+type frameConn interface {
+	Read(ctx context.Context) (string, error)
+	Write(ctx context.Context, data string) error
+	Close() error
+}
+
+type wsConn struct {
+	c *websocket.Conn
+}
+
+func (w *wsConn) Read(ctx context.Context) (string, error) {
+	_, b, err := w.c.Read(ctx)
+	return string(b), err
+}
+
+func (w *wsConn) Write(ctx context.Context, data string) error {
+	return w.c.Write(ctx, websocket.MessageText, []byte(data))
+}
+
+func (w *wsConn) Close() error {
+	return w.c.CloseNow()
+}
+
+// Option configures optional behavior on a Client constructed by New.
+type Option func(*Client)
+
+// WithReconnectPolicy overrides the default ReconnectPolicy used when the
+// underlying connection is lost.
+func WithReconnectPolicy(p ReconnectPolicy) Option {
+	return func(c *Client) { c.reconnect = p }
+}
+
+// WithOnConnect registers a callback invoked every time the client
+// establishes, or re-establishes, a connection.
+func WithOnConnect(f func()) Option {
+	return func(c *Client) { c.onConnect = f }
+}
+
+// WithOnDisconnect registers a callback invoked whenever the connection is
+// lost, with the error that caused it.
+func WithOnDisconnect(f func(err error)) Option {
+	return func(c *Client) { c.onDisconnect = f }
+}
+
+// WithOnReconnect registers a callback invoked before each reconnect
+// attempt. attempt starts at 1.
+func WithOnReconnect(f func(attempt int)) Option {
+	return func(c *Client) { c.onReconnect = f }
+}
+
 // New creates a Client for receiving events from a Socket.IO server.
-func New(server string, ts oauth2.TokenSource) *Client {
-	return &Client{
+func New(server string, ts oauth2.TokenSource, opts ...Option) *Client {
+	c := &Client{
 		server:      server,
 		namespace:   "/",
 		tokenSource: ts,
+		reconnect:   DefaultReconnectPolicy(),
+		transport:   Transport,
+		acks:        make(map[int]chan []json.RawMessage),
+		logger:      slog.Default(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 type Client struct {
 	server      string
 	namespace   string
 	tokenSource oauth2.TokenSource
+	transport   string
+
+	reconnect ReconnectPolicy
+
+	onConnect    func()
+	onDisconnect func(err error)
+	onReconnect  func(attempt int)
+
+	logger  *slog.Logger
+	metrics Metrics
+
+	connMu sync.RWMutex
+	conn   frameConn
+
+	ackSeq int64
+	acksMu sync.Mutex
+	acks   map[int]chan []json.RawMessage
+
+	lastPing time.Time
 }
 
-func (c *Client) HandleEvents(ctx context.Context, h func(name string, msg string) error) error {
+func (c *Client) setConn(conn frameConn) {
+	c.connMu.Lock()
+	c.conn = conn
+	c.connMu.Unlock()
+}
+
+func (c *Client) activeConn() frameConn {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.conn
+}
+
+// handlerError wraps an error returned by HandleEvents' callback, so
+// HandleEvents can tell it apart from connection-level errors that should
+// trigger a reconnect.
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string { return e.err.Error() }
+func (e *handlerError) Unwrap() error { return e.err }
+
+// EventHandler handles a single SocketIO event. The returned values, if
+// any, are sent back to the server as an acknowledgement when the event
+// carried an ack id.
+type EventHandler func(name string, msg string) ([]any, error)
+
+// HandleEvents connects to the configured Socket.IO server and invokes h for
+// every event received. If the underlying connection is lost, it is
+// automatically redialed using c.reconnect. HandleEvents only returns once
+// ctx is done, h returns an error, or the reconnect policy's attempt budget
+// is exhausted.
+func (c *Client) HandleEvents(ctx context.Context, h EventHandler) error {
+	var attempt int
+
+	for {
+		err := c.connectAndHandle(ctx, h, func() {
+			attempt = 0
+			if c.onConnect != nil {
+				c.onConnect()
+			}
+		})
+
+		var hErr *handlerError
+		if errors.As(err, &hErr) {
+			return hErr.err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if c.onDisconnect != nil {
+			c.onDisconnect(err)
+		}
+
+		if c.reconnect.MaxAttempts > 0 && attempt >= c.reconnect.MaxAttempts {
+			return fmt.Errorf("reconnect attempts exhausted: %w", err)
+		}
+
+		delay := c.reconnect.delay(attempt)
+		attempt++
+
+		if c.metrics != nil {
+			c.metrics.IncReconnect()
+		}
+
+		c.logger.Warn("Socket.IO connection lost, will reconnect", "error", err, "attempt", attempt, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if c.onReconnect != nil {
+			c.onReconnect(attempt)
+		}
+	}
+}
+
+// dial establishes a single connection using the configured transport and
+// returns it along with the EngineIO open packet, if it was already
+// observed as part of the transport's handshake (long-polling always knows
+// it up front; websocket only learns it once the first packet arrives).
+func (c *Client) dial(ctx context.Context) (frameConn, *eioOpenPacket, error) {
 	u, err := url.Parse(c.server)
 	if err != nil {
-		return fmt.Errorf("invalid url: %w", err)
+		return nil, nil, fmt.Errorf("invalid url: %w", err)
 	}
 
 	q := u.Query()
 	q.Set("EIO", EngineIOVersion)
-	q.Set("transport", Transport)
 
 	if c.tokenSource != nil {
 		t, err := c.tokenSource.Token()
 		if err != nil {
-			return fmt.Errorf("get token: %w", err)
+			return nil, nil, fmt.Errorf("get token: %w", err)
 		}
 
 		q.Set("token", fmt.Sprintf("Bearer %s", t.AccessToken))
 	}
 
+	if c.transport == TransportPolling {
+		q.Set("transport", TransportPolling)
+		u.RawQuery = q.Encode()
+
+		pc, open, err := dialPolling(ctx, *u)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var conn frameConn = pc
+
+		if contains(open.Upgrades, Transport) {
+			if ws, err := upgradeToWebsocket(ctx, *u, pc.sid); err != nil {
+				c.logger.Debug("Could not upgrade to websocket, staying on long-polling", "error", err)
+			} else {
+				conn = ws
+			}
+		}
+
+		return conn, &open, nil
+	}
+
+	q.Set("transport", Transport)
 	u.RawQuery = q.Encode()
 
-	conn, _, err := websocket.Dial(ctx, u.String(), nil)
+	wsc, _, err := websocket.Dial(ctx, u.String(), nil)
 	if err != nil {
-		return fmt.Errorf("websocket dial: %w", err)
+		return nil, nil, fmt.Errorf("websocket dial: %w", err)
+	}
+
+	return &wsConn{c: wsc}, nil, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
 	}
 
+	return false
+}
+
+// connectAndHandle dials a single connection, handles its handshake and
+// read loop, and returns once the connection is lost or h returns an error.
+// connected is called once the SocketIO namespace connect has been sent.
+func (c *Client) connectAndHandle(ctx context.Context, h EventHandler, connected func()) error {
+	conn, open, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.setConn(conn)
+
 	defer func() {
-		if err := conn.CloseNow(); err != nil {
-			slog.Error("Errored while closing websocket connection", "error", err)
+		c.setConn(nil)
+
+		if err := conn.Close(); err != nil {
+			c.logger.Error("Errored while closing connection", "error", err)
 		}
 	}()
 
 	// SocketIO connection request
-	if err := conn.Write(ctx, websocket.MessageText, []byte("40")); err != nil {
+	if err := conn.Write(ctx, "40"); err != nil {
 		return fmt.Errorf("socketio connect to namespace: %w", err)
 	}
 
+	connected()
+
+	// readCtx is cancelled by the ping watchdog below when the server stops
+	// pinging, so Read doesn't block forever on a half-dead connection.
+	readCtx, cancelRead := context.WithCancel(ctx)
+	defer cancelRead()
+
+	var (
+		watchdog        *time.Timer
+		watchdogTimeout time.Duration
+	)
+	defer func() {
+		if watchdog != nil {
+			watchdog.Stop()
+		}
+	}()
+
+	armWatchdog := func() {
+		if watchdogTimeout <= 0 {
+			return
+		}
+
+		if watchdog == nil {
+			watchdog = time.AfterFunc(watchdogTimeout, cancelRead)
+			return
+		}
+
+		watchdog.Reset(watchdogTimeout)
+	}
+
+	if open != nil {
+		watchdogTimeout = time.Duration(open.PingInterval+open.PingTimeout) * time.Millisecond
+		armWatchdog()
+	}
+
 	for {
-		_, b, err := conn.Read(ctx)
+		s, err := conn.Read(readCtx)
 		if err != nil {
+			if ctx.Err() == nil && readCtx.Err() != nil {
+				return fmt.Errorf("no ping received before watchdog timeout: %w", err)
+			}
 			return fmt.Errorf("read: %w", err)
 		}
 
-		s := string(b)
-
-		slog.Debug("Got websocket packet", "packet", s)
+		c.logger.Debug("Got packet", "packet", s)
 
 		// We only care about EngineIO packets. They start with the message type number
 		if len(s) < 1 {
-			slog.Debug("Packet has no data")
+			c.logger.Debug("Packet has no data")
 			continue
 		}
 
 		eioType, err := strconv.Atoi(string(s[0]))
 		if err != nil {
-			slog.Debug("Invalid EngineIO type", "type", s[0])
+			c.incDecodeError()
+			c.logger.Debug("Invalid EngineIO type", "type", s[0])
+			continue
+		}
+
+		if PacketType(eioType) == EIOPacketTypeOpen {
+			var o eioOpenPacket
+			if err := json.Unmarshal([]byte(s[1:]), &o); err != nil {
+				c.incDecodeError()
+				c.logger.Debug("Could not unmarshal EngineIO open packet", "error", err)
+				continue
+			}
+
+			watchdogTimeout = time.Duration(o.PingInterval+o.PingTimeout) * time.Millisecond
+			armWatchdog()
 			continue
 		}
 
 		if PacketType(eioType) == EIOPacketTypePing {
-			slog.Debug("Got EngineIO Ping, will Pong")
-			if err := conn.Write(ctx, websocket.MessageText, []byte("3")); err != nil {
+			c.logger.Debug("Got EngineIO Ping, will Pong")
+			armWatchdog()
+
+			if c.metrics != nil && !c.lastPing.IsZero() {
+				c.metrics.ObservePingLatency(time.Since(c.lastPing))
+			}
+			c.lastPing = time.Now()
+
+			if err := conn.Write(ctx, "3"); err != nil {
 				return fmt.Errorf("eio pong: %w", err)
 			}
 
-			slog.Debug("Ponged")
+			c.logger.Debug("Ponged")
 			continue
 		}
 
 		if len(s) < 2 {
 			// it has no data so we don't care
-			slog.Debug("Message is not SocketIO message")
+			c.logger.Debug("Message is not SocketIO message")
 			continue
 		}
 
 		sioType, err := strconv.Atoi(string(s[1]))
 		if err != nil {
-			slog.Debug("Invalid SocketIO type", "type", s[1])
+			c.incDecodeError()
+			c.logger.Debug("Invalid SocketIO type", "type", s[1])
 			continue
 		}
 
-		if PacketType(sioType) != PacketTypeEvent || len(s) < 3 {
-			slog.Debug("Skipping non event SocketIO packet")
+		ackID, payload := parseAckID(s[2:])
+
+		if PacketType(sioType) == PacketTypeAck {
+			var values []json.RawMessage
+			if err := json.Unmarshal([]byte(payload), &values); err != nil {
+				c.incDecodeError()
+				c.logger.Error("Could not unmarshal SocketIO ack", "error", err)
+				continue
+			}
+
+			if ackID != nil {
+				c.resolveAck(*ackID, values)
+			}
+
+			continue
+		}
+
+		if PacketType(sioType) != PacketTypeEvent || payload == "" {
+			c.logger.Debug("Skipping non event SocketIO packet")
 			continue
 		}
 
 		var values []json.RawMessage
-		if err := json.Unmarshal([]byte(s[2:]), &values); err != nil {
-			slog.Error("Could not unmarshal SocketIO event", "error", err)
+		if err := json.Unmarshal([]byte(payload), &values); err != nil {
+			c.incDecodeError()
+			c.logger.Error("Could not unmarshal SocketIO event", "error", err)
 			continue
 		}
 
 		if len(values) < 2 {
-			slog.Error("Got unexpected number of values from SocketIO event", "values", values)
+			c.incDecodeError()
+			c.logger.Error("Got unexpected number of values from SocketIO event", "values", values)
 			continue
 		}
 
 		var name string
 		if err := json.Unmarshal(values[0], &name); err != nil {
-			slog.Error("Failed to unmarshal event name", "error", err)
+			c.incDecodeError()
+			c.logger.Error("Failed to unmarshal event name", "error", err)
 			continue
 		}
 
 		data, err := values[1].MarshalJSON()
 		if err != nil {
-			slog.Error("Failed to handle message body", "error", err)
+			c.incDecodeError()
+			c.logger.Error("Failed to handle message body", "error", err)
 			continue
 		}
 
-		if err := h(name, string(data)); err != nil {
-			return err
+		if c.metrics != nil {
+			c.metrics.IncEventsReceived(name)
+		}
+
+		result, err := h(name, string(data))
+		if err != nil {
+			return &handlerError{err: err}
+		}
+
+		if ackID != nil {
+			if err := c.sendAck(ctx, conn, *ackID, result); err != nil {
+				return fmt.Errorf("send ack: %w", err)
+			}
 		}
 	}
 }
@@ -0,0 +1,227 @@
+package homely
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StateStore maintains an in-memory, thread-safe model of every Device in a
+// location. Seed it with a LocationDetails snapshot and feed it the Events
+// from Client.Stream to keep it up to date:
+//
+//	details, _ := c.LocationDetails(ctx, locationID)
+//	store := homely.NewStateStore(details)
+//	c.Stream(ctx, locationID, func(e homely.Event) {
+//		store.Apply(e)
+//	})
+//
+// StateStore exposes typed accessors for common feature/state combinations
+// as well as per-device, per-feature subscriptions, so callers don't have to
+// re-implement state merging on top of the raw event stream.
+type StateStore struct {
+	mu      sync.RWMutex
+	devices map[uuid.UUID]Device
+
+	subMu sync.Mutex
+	subs  map[subKey][]chan Change
+}
+
+type subKey struct {
+	deviceID uuid.UUID
+	feature  string
+}
+
+// NewStateStore creates a StateStore seeded with the devices found in d.
+func NewStateStore(d LocationDetails) *StateStore {
+	s := &StateStore{
+		devices: make(map[uuid.UUID]Device, len(d.Devices)),
+		subs:    make(map[subKey][]chan Change),
+	}
+
+	for _, dev := range d.Devices {
+		s.devices[dev.ID] = copyDevice(dev)
+	}
+
+	return s
+}
+
+// Apply merges the changes carried by e into the store and notifies any
+// subscribers of the affected device and features.
+func (s *StateStore) Apply(e Event) {
+	s.mu.Lock()
+	dev, ok := s.devices[e.Data.DeviceID]
+	if ok {
+		for _, c := range e.Data.Changes {
+			dev = applyChange(dev, c)
+		}
+		s.devices[e.Data.DeviceID] = dev
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	for _, c := range e.Data.Changes {
+		s.notify(e.Data.DeviceID, c)
+	}
+}
+
+func applyChange(dev Device, c Change) Device {
+	if dev.Features == nil {
+		dev.Features = map[string]Feature{}
+	}
+
+	f := dev.Features[c.Feature]
+	if f.States == nil {
+		f.States = map[string]State{}
+	}
+
+	f.States[c.StateName] = State{Value: c.Value, LastUpdated: c.LastUpdated}
+	dev.Features[c.Feature] = f
+
+	return dev
+}
+
+// Device returns the last known state of deviceID. The returned Device is a
+// deep copy, so the caller can read it, including its Features and States
+// maps, without racing Apply.
+func (s *StateStore) Device(deviceID uuid.UUID) (Device, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dev, ok := s.devices[deviceID]
+	if !ok {
+		return Device{}, false
+	}
+
+	return copyDevice(dev), true
+}
+
+func copyDevice(dev Device) Device {
+	if dev.Features == nil {
+		return dev
+	}
+
+	features := make(map[string]Feature, len(dev.Features))
+	for name, f := range dev.Features {
+		states := make(map[string]State, len(f.States))
+		for stateName, st := range f.States {
+			states[stateName] = st
+		}
+
+		features[name] = Feature{States: states}
+	}
+
+	dev.Features = features
+
+	return dev
+}
+
+// TemperatureC returns the last reported temperature, in Celsius, for
+// deviceID and the time it was last updated.
+func (s *StateStore) TemperatureC(deviceID uuid.UUID) (float64, time.Time, bool) {
+	st, ok := s.state(deviceID, "temperature", "temperature")
+	if !ok {
+		return 0, time.Time{}, false
+	}
+
+	v, ok := st.Value.(float64)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+
+	return v, st.LastUpdated, true
+}
+
+// AlarmTriggered reports whether deviceID's alarm feature is currently
+// triggered, along with the time it was last updated.
+func (s *StateStore) AlarmTriggered(deviceID uuid.UUID) (bool, time.Time, bool) {
+	st, ok := s.state(deviceID, "alarm", "alarm")
+	if !ok {
+		return false, time.Time{}, false
+	}
+
+	v, ok := st.Value.(bool)
+	if !ok {
+		return false, time.Time{}, false
+	}
+
+	return v, st.LastUpdated, true
+}
+
+// BatteryLow reports whether deviceID's battery feature is currently
+// reporting a low battery, along with the time it was last updated.
+func (s *StateStore) BatteryLow(deviceID uuid.UUID) (bool, time.Time, bool) {
+	st, ok := s.state(deviceID, "battery", "low")
+	if !ok {
+		return false, time.Time{}, false
+	}
+
+	v, ok := st.Value.(bool)
+	if !ok {
+		return false, time.Time{}, false
+	}
+
+	return v, st.LastUpdated, true
+}
+
+func (s *StateStore) state(deviceID uuid.UUID, feature, stateName string) (State, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dev, ok := s.devices[deviceID]
+	if !ok {
+		return State{}, false
+	}
+
+	f, ok := dev.Features[feature]
+	if !ok {
+		return State{}, false
+	}
+
+	st, ok := f.States[stateName]
+	return st, ok
+}
+
+// Subscribe returns a channel that receives a Change every time feature on
+// deviceID is updated. The channel is buffered by one and drops updates
+// rather than blocking Apply if the caller isn't keeping up. Call the
+// returned cancel function to release the subscription once it's no longer
+// needed.
+func (s *StateStore) Subscribe(deviceID uuid.UUID, feature string) (ch <-chan Change, cancel func()) {
+	key := subKey{deviceID: deviceID, feature: feature}
+	c := make(chan Change, 1)
+
+	s.subMu.Lock()
+	s.subs[key] = append(s.subs[key], c)
+	s.subMu.Unlock()
+
+	return c, func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+
+		chans := s.subs[key]
+		for i, existing := range chans {
+			if existing == c {
+				s.subs[key] = append(chans[:i], chans[i+1:]...)
+				close(c)
+				break
+			}
+		}
+	}
+}
+
+func (s *StateStore) notify(deviceID uuid.UUID, c Change) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for _, ch := range s.subs[subKey{deviceID: deviceID, feature: c.Feature}] {
+		select {
+		case ch <- c:
+		default:
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package homely
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestApplyChange(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name string
+		dev  Device
+		c    Change
+		want State
+	}{
+		{
+			name: "nil features",
+			dev:  Device{},
+			c:    Change{Feature: "temperature", StateName: "temperature", Value: 21.5, LastUpdated: now},
+			want: State{Value: 21.5, LastUpdated: now},
+		},
+		{
+			name: "existing feature, existing state",
+			dev: Device{Features: map[string]Feature{
+				"temperature": {States: map[string]State{"temperature": {Value: 20.0}}},
+			}},
+			c:    Change{Feature: "temperature", StateName: "temperature", Value: 22.0, LastUpdated: now},
+			want: State{Value: 22.0, LastUpdated: now},
+		},
+		{
+			name: "new feature alongside existing one",
+			dev: Device{Features: map[string]Feature{
+				"alarm": {States: map[string]State{"alarm": {Value: false}}},
+			}},
+			c:    Change{Feature: "battery", StateName: "low", Value: true, LastUpdated: now},
+			want: State{Value: true, LastUpdated: now},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := applyChange(c.dev, c.c)
+
+			st := got.Features[c.c.Feature].States[c.c.StateName]
+			if st != c.want {
+				t.Errorf("applyChange: got state %+v, want %+v", st, c.want)
+			}
+		})
+	}
+}
+
+func TestCopyDevice(t *testing.T) {
+	orig := Device{
+		ID: uuid.New(),
+		Features: map[string]Feature{
+			"temperature": {States: map[string]State{"temperature": {Value: 20.0}}},
+		},
+	}
+
+	cp := copyDevice(orig)
+
+	cp.Features["temperature"] = Feature{States: map[string]State{"temperature": {Value: 99.0}}}
+
+	if v := orig.Features["temperature"].States["temperature"].Value; v != 20.0 {
+		t.Errorf("mutating copy changed original: original states = %v, want unchanged at 20.0", v)
+	}
+}
+
+func TestCopyDeviceNilFeatures(t *testing.T) {
+	orig := Device{ID: uuid.New()}
+
+	cp := copyDevice(orig)
+
+	if cp.Features != nil {
+		t.Errorf("copyDevice of nil Features = %v, want nil", cp.Features)
+	}
+}
+
+func TestNewStateStoreCopiesSeedDevices(t *testing.T) {
+	id := uuid.New()
+	details := LocationDetails{
+		Devices: []Device{
+			{ID: id, Features: map[string]Feature{
+				"temperature": {States: map[string]State{"temperature": {Value: 20.0}}},
+			}},
+		},
+	}
+
+	storeA := NewStateStore(details)
+	storeB := NewStateStore(details)
+
+	storeA.Apply(Event{Data: EventData{
+		DeviceID: id,
+		Changes:  []Change{{Feature: "temperature", StateName: "temperature", Value: 30.0}},
+	}})
+
+	v, _, ok := storeB.TemperatureC(id)
+	if !ok {
+		t.Fatalf("storeB.TemperatureC: device not found")
+	}
+
+	if v != 20.0 {
+		t.Errorf("storeB temperature = %v after storeA.Apply, want unchanged at 20.0", v)
+	}
+
+	if details.Devices[0].Features["temperature"].States["temperature"].Value != 20.0 {
+		t.Errorf("Apply mutated the LocationDetails passed to NewStateStore")
+	}
+}
+
+func TestStateStoreSubscribe(t *testing.T) {
+	id := uuid.New()
+	store := NewStateStore(LocationDetails{Devices: []Device{{ID: id}}})
+
+	ch, cancel := store.Subscribe(id, "alarm")
+	defer cancel()
+
+	store.Apply(Event{Data: EventData{
+		DeviceID: id,
+		Changes:  []Change{{Feature: "alarm", StateName: "alarm", Value: true}},
+	}})
+
+	select {
+	case c := <-ch:
+		if c.Value != true {
+			t.Errorf("Change.Value = %v, want true", c.Value)
+		}
+	default:
+		t.Fatal("expected a Change on the subscription channel")
+	}
+}
+
+func TestStateStoreSubscribeCancel(t *testing.T) {
+	id := uuid.New()
+	store := NewStateStore(LocationDetails{Devices: []Device{{ID: id}}})
+
+	ch, cancel := store.Subscribe(id, "alarm")
+	cancel()
+
+	if _, ok := <-ch; ok {
+		t.Errorf("expected channel to be closed after cancel")
+	}
+
+	// Apply must not panic or block after the subscriber cancelled.
+	store.Apply(Event{Data: EventData{
+		DeviceID: id,
+		Changes:  []Change{{Feature: "alarm", StateName: "alarm", Value: true}},
+	}})
+}